@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// JobStatus is the lifecycle state of an async conversion job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+const (
+	// JobTTL is how long a finished (done/failed) job's record and /tmp
+	// artifacts are kept around before the janitor sweeps them.
+	JobTTL = 15 * time.Minute
+	// JanitorInterval is how often the janitor sweep runs.
+	JanitorInterval = time.Minute
+)
+
+// Job tracks one /jobs conversion request from enqueue to completion.
+// It is mutated by the dispatcher worker that owns it and read by the
+// /jobs/{id} and /jobs/{id}/result handlers, so all access goes through
+// the JobStore rather than touching fields directly.
+type Job struct {
+	ID       string    `json:"job_id"`
+	Status   JobStatus `json:"status"`
+	Progress float64   `json:"progress"`
+
+	DurationSeconds float64 `json:"-"`
+
+	Profile    string `json:"profile"`
+	Filename   string `json:"filename"`
+	InputPath  string `json:"-"`
+	OutputPath string `json:"-"`
+	OutputSize int64  `json:"output_size,omitempty"`
+
+	// Destination is set for jobs created via the remote (object-store)
+	// API in objectstore.go/remotejobs.go; when set, runJob uploads the
+	// result there instead of leaving it for /jobs/{id}/result to stream.
+	Destination *ObjectRef `json:"-"`
+	ResultURL   string     `json:"result_url,omitempty"`
+	ResultETag  string     `json:"result_etag,omitempty"`
+
+	Error   string   `json:"error,omitempty"`
+	LogTail []string `json:"log_tail,omitempty"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// JobStore persists job records. The default is an in-memory map; the
+// interface exists so a BadgerDB or Redis-backed store can be dropped in
+// later without touching the dispatcher or handlers.
+type JobStore interface {
+	Create(job *Job)
+	Get(id string) (*Job, bool)
+	Update(id string, fn func(*Job))
+	Delete(id string)
+	Sweep(olderThan time.Time)
+}
+
+// MemoryJobStore is the default JobStore, backed by an in-process map.
+// Job state does not survive a worker restart.
+type MemoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]*Job)}
+}
+
+func (s *MemoryJobStore) Create(job *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+}
+
+func (s *MemoryJobStore) Get(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	clone := *job
+	return &clone, true
+}
+
+func (s *MemoryJobStore) Update(id string, fn func(*Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (s *MemoryJobStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+}
+
+// Sweep removes finished jobs older than the cutoff and best-effort
+// cleans up any /tmp artifacts they still reference.
+func (s *MemoryJobStore) Sweep(olderThan time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, job := range s.jobs {
+		if job.Status != JobDone && job.Status != JobFailed {
+			continue
+		}
+		if job.FinishedAt.After(olderThan) {
+			continue
+		}
+		if job.InputPath != "" {
+			os.Remove(job.InputPath)
+		}
+		if job.OutputPath != "" {
+			if profiles[job.Profile].IsDirectory {
+				os.RemoveAll(job.OutputPath)
+			} else {
+				os.Remove(job.OutputPath)
+			}
+		}
+		delete(s.jobs, id)
+	}
+}
+
+// requireAuth checks the Bearer token against WORKER_SECRET and writes a
+// structured JSON error if it's missing/invalid. Every /jobs/* route must
+// call this before touching job state — job IDs are random but still
+// appear in logs/proxies/Referer headers, so polling status, streaming
+// progress, or downloading output must not be left unauthenticated just
+// because the route only reads.
+func requireAuth(w http.ResponseWriter, r *http.Request, l *zerolog.Logger) bool {
+	workerSecret := os.Getenv("WORKER_SECRET")
+	if workerSecret == "" {
+		l.Error().Msg("WORKER_SECRET is not set in environment")
+		writeJSONError(w, http.StatusInternalServerError, "Server Configuration Error")
+		return false
+	}
+	if r.Header.Get("Authorization") != "Bearer "+workerSecret {
+		l.Warn().Msg("unauthorized attempt")
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	return true
+}
+
+// jobStore is the process-wide job record store.
+var jobStore JobStore = NewMemoryJobStore()
+
+// jobWorkQueue carries work items rather than just acting as a semaphore,
+// so the dispatcher can pull real jobs off it instead of a bare struct{}.
+var jobWorkQueue = make(chan *Job, 64)
+
+// StartDispatcher launches the bounded worker pool that actually runs
+// ffmpeg for queued jobs, and the janitor that GCs finished ones.
+func StartDispatcher(workers int) {
+	for i := 0; i < workers; i++ {
+		go jobWorker(i)
+	}
+	go janitorLoop()
+}
+
+func jobWorker(workerNum int) {
+	for job := range jobWorkQueue {
+		queueDepth.Dec()
+		queueWaitSeconds.Observe(time.Since(job.CreatedAt).Seconds())
+		jobsInflight.Inc()
+		runJob(job)
+		jobsInflight.Dec()
+	}
+	_ = workerNum
+}
+
+func janitorLoop() {
+	ticker := time.NewTicker(JanitorInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		jobStore.Sweep(time.Now().Add(-JobTTL))
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// runJob performs the actual ffmpeg conversion for a queued job and
+// updates its record as it progresses. It replaces the inline logic that
+// used to live directly in the /convert-webp handler.
+func runJob(job *Job) {
+	jobStore.Update(job.ID, func(j *Job) {
+		j.Status = JobRunning
+		j.StartedAt = time.Now()
+	})
+	logger.Info().Str("job_id", job.ID).Str("filename", job.Filename).Str("phase", "convert_start").Msg("starting conversion")
+
+	// We need the total duration up front so progress events can be
+	// expressed as a percentage rather than a bare timestamp.
+	var durationSeconds float64
+	if probed, err := ProbeFile(context.Background(), job.InputPath, 15*time.Second); err == nil {
+		durationSeconds = probed.DurationSeconds()
+		jobStore.Update(job.ID, func(j *Job) { j.DurationSeconds = durationSeconds })
+	} else {
+		logger.Warn().Str("job_id", job.ID).Str("phase", "probe").Err(err).Msg("could not probe duration, progress will be unavailable")
+	}
+
+	profile := profiles[job.Profile]
+
+	var outputPath string
+	if profile.IsDirectory {
+		outputPath = job.InputPath + "_" + profile.Name
+		if err := os.MkdirAll(outputPath, 0o755); err != nil {
+			failJob(job.ID, "failed to create output directory", nil)
+			logger.Error().Str("job_id", job.ID).Err(err).Msg("could not create hls output dir")
+			return
+		}
+	} else {
+		outputPath = job.InputPath + profile.Extension
+	}
+
+	args := append([]string{"-y", "-progress", "pipe:1", "-nostats", "-i", job.InputPath}, profile.BuildArgs(outputPath)...)
+	cmd := exec.Command("ffmpeg", args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		failJob(job.ID, "internal error", nil)
+		logger.Error().Str("job_id", job.ID).Err(err).Msg("could not attach stdout pipe")
+		return
+	}
+	var stderrBuf bytes.Buffer
+	cmd.Stderr = &stderrBuf
+
+	ffmpegStart := time.Now()
+	if err := cmd.Start(); err != nil {
+		failJob(job.ID, "failed to start ffmpeg", nil)
+		logger.Error().Str("job_id", job.ID).Err(err).Msg("could not start ffmpeg")
+		return
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	parseProgressStream(scanner, durationSeconds, func(ev ProgressEvent, done bool) {
+		jobStore.Update(job.ID, func(j *Job) { j.Progress = ev.Percent })
+		hub.publish(job.ID, ev)
+	})
+
+	ffmpegDurationSeconds.WithLabelValues(job.Profile).Observe(time.Since(ffmpegStart).Seconds())
+
+	if err := cmd.Wait(); err != nil {
+		logger.Error().Str("job_id", job.ID).Str("phase", "convert").Str("ffmpeg_log", stderrBuf.String()).Msg("ffmpeg failed")
+		failJob(job.ID, "conversion failed", tailLines(stderrBuf.String(), 20))
+		hub.publish(job.ID, ProgressEvent{Status: "done"})
+		return
+	}
+
+	stat, _ := os.Stat(outputPath)
+	var size int64
+	if stat != nil {
+		size = stat.Size()
+	}
+	outputBytes.Observe(float64(size))
+
+	var resultURL, resultETag string
+	if job.Destination != nil && !profile.IsDirectory {
+		etag, err := uploadResult(*job.Destination, outputPath, profile.MIMEType)
+		if err != nil {
+			logger.Error().Str("job_id", job.ID).Err(err).Msg("failed to upload result to destination")
+			failJob(job.ID, "failed to upload result: "+err.Error(), tailLines(stderrBuf.String(), 20))
+			hub.publish(job.ID, ProgressEvent{Status: "done"})
+			return
+		}
+		resultURL, resultETag = job.Destination.String(), etag
+	}
+
+	jobStore.Update(job.ID, func(j *Job) {
+		j.Status = JobDone
+		j.Progress = 100
+		j.OutputPath = outputPath
+		j.OutputSize = size
+		j.ResultURL = resultURL
+		j.ResultETag = resultETag
+		j.LogTail = tailLines(stderrBuf.String(), 20)
+		j.FinishedAt = time.Now()
+	})
+	hub.publish(job.ID, ProgressEvent{Status: "done", Percent: 100})
+	logger.Info().Str("job_id", job.ID).Str("phase", "convert_done").
+		Dur("duration_ms", time.Since(ffmpegStart)).
+		Int64("size_bytes", size).
+		Msg("conversion complete")
+}
+
+// uploadResult streams a finished job's output file to its destination
+// ObjectRef, returning the store-reported ETag (empty for stores that
+// don't have one).
+func uploadResult(dest ObjectRef, outputPath, contentType string) (string, error) {
+	store, err := storeForRef(dest)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(outputPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return store.Put(context.Background(), dest, f, contentType)
+}
+
+func failJob(jobID, errMsg string, logTail []string) {
+	jobStore.Update(jobID, func(j *Job) {
+		j.Status = JobFailed
+		j.Error = errMsg
+		j.LogTail = logTail
+		j.FinishedAt = time.Now()
+	})
+}
+
+func tailLines(s string, n int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) <= n {
+		return lines
+	}
+	return lines[len(lines)-n:]
+}
+
+// handleCreateJob implements POST /jobs: it accepts the same multipart
+// upload /convert-webp used to, stages the input, and hands a Job off to
+// the dispatcher instead of running ffmpeg inline. Returns immediately.
+func handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	id := nextRequestID()
+	l := logger.With().Int64("request_id", id).Str("remote_addr", r.RemoteAddr).Logger()
+	l.Info().Str("phase", "submit").Msg("new job submission")
+
+	if !requireAuth(w, r, &l) {
+		return
+	}
+	if r.Method != "POST" {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// FormValue parses the multipart body, so the size cap has to be in
+	// place before it runs rather than only inside SaveUpload.
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadBytes())
+	profileName := r.FormValue("profile")
+	if profileName == "" {
+		profileName = DefaultProfile
+	}
+	if _, ok := profiles[profileName]; !ok {
+		l.Warn().Str("profile", profileName).Msg("unknown profile")
+		writeJSONError(w, http.StatusBadRequest, "Unknown profile: "+profileName)
+		return
+	}
+
+	jobID := newJobID()
+	inputPath, cleanup, ingestErr := SaveUpload(w, r, "job_"+jobID)
+	if ingestErr != nil {
+		l.Warn().Str("phase", "ingest").Err(ingestErr).Msg("ingestion failed")
+		writeJSONError(w, ingestErr.StatusCode, ingestErr.Message)
+		return
+	}
+	if stat, err := os.Stat(inputPath); err == nil {
+		inputBytes.Observe(float64(stat.Size()))
+	}
+
+	if probed, err := ProbeFile(r.Context(), inputPath, 15*time.Second); err == nil {
+		if max := MaxDurationSeconds(); probed.DurationSeconds() > max {
+			cleanup()
+			l.Warn().Float64("duration_seconds", probed.DurationSeconds()).Float64("cap_seconds", max).Msg("rejected: duration exceeds cap")
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("input duration %.1fs exceeds the %.1fs cap", probed.DurationSeconds(), max))
+			return
+		}
+	} else {
+		l.Warn().Str("phase", "probe").Err(err).Msg("could not pre-flight duration")
+	}
+
+	job := &Job{
+		ID:        jobID,
+		Status:    JobQueued,
+		Profile:   profileName,
+		Filename:  filepath.Base(inputPath),
+		InputPath: inputPath,
+		CreatedAt: time.Now(),
+	}
+	jobStore.Create(job)
+
+	select {
+	case jobWorkQueue <- job:
+		queueDepth.Inc()
+		l.Info().Str("job_id", jobID).Msg("job queued")
+	default:
+		jobStore.Update(jobID, func(j *Job) {
+			j.Status = JobFailed
+			j.Error = "queue is full"
+			j.FinishedAt = time.Now()
+		})
+		l.Error().Str("job_id", jobID).Msg("job rejected: queue full")
+		writeJSONError(w, http.StatusServiceUnavailable, "Queue is full, try again later")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id":     jobID,
+		"status_url": "/jobs/" + jobID,
+	})
+}
+
+// handleJobStatus implements GET /jobs/{id}.
+func handleJobStatus(w http.ResponseWriter, r *http.Request, jobID string) {
+	job, ok := jobStore.Get(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleJobResult implements GET /jobs/{id}/result, streaming the
+// finished output file back to the caller with its profile's MIME type.
+func handleJobResult(w http.ResponseWriter, r *http.Request, jobID string) {
+	job, ok := jobStore.Get(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	switch job.Status {
+	case JobQueued, JobRunning:
+		http.Error(w, "Job is still processing", http.StatusConflict)
+		return
+	case JobFailed:
+		http.Error(w, "Job failed: "+job.Error, http.StatusUnprocessableEntity)
+		return
+	}
+
+	profile := profiles[job.Profile]
+	if profile.IsDirectory {
+		http.Error(w, "This profile produces a directory; fetch it under /jobs/"+job.ID+"/hls/", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", profile.MIMEType)
+	http.ServeFile(w, r, job.OutputPath)
+}
+
+// handleJobHLS serves the segment/playlist files produced by the "hls"
+// profile under /jobs/{id}/hls/{file}.
+func handleJobHLS(w http.ResponseWriter, r *http.Request, jobID, file string) {
+	job, ok := jobStore.Get(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if job.Status != JobDone {
+		http.Error(w, "Job is still processing", http.StatusConflict)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(job.OutputPath, filepath.Base(file)))
+}
+
+// handleJobs routes the /jobs/* tree by method and path shape, since the
+// stdlib mux used in main.go doesn't do path params.
+func handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/jobs" {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			handleCreateRemoteJob(w, r)
+			return
+		}
+		handleCreateJob(w, r)
+		return
+	}
+
+	l := logger.With().Str("remote_addr", r.RemoteAddr).Logger()
+	if !requireAuth(w, r, &l) {
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	if strings.HasSuffix(rest, "/result") {
+		handleJobResult(w, r, strings.TrimSuffix(rest, "/result"))
+		return
+	}
+	if strings.HasSuffix(rest, "/events") {
+		handleJobEvents(w, r, strings.TrimSuffix(rest, "/events"))
+		return
+	}
+	if idx := strings.Index(rest, "/hls/"); idx != -1 {
+		handleJobHLS(w, r, rest[:idx], rest[idx+len("/hls/"):])
+		return
+	}
+	handleJobStatus(w, r, strings.TrimSuffix(rest, "/"))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		n     int
+		want  []string
+	}{
+		{"fewer lines than n", "a\nb\n", 5, []string{"a", "b"}},
+		{"exactly n lines", "a\nb\nc\n", 3, []string{"a", "b", "c"}},
+		{"more lines than n", "a\nb\nc\nd\n", 2, []string{"c", "d"}},
+		{"no trailing newline", "a\nb\nc", 2, []string{"b", "c"}},
+		{"single line", "only", 3, []string{"only"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tailLines(tt.input, tt.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tailLines(%q, %d) = %v, want %v", tt.input, tt.n, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// logger is the process-wide structured logger. JSON output is the
+// default so ops can grep/aggregate by field (request_id, phase, ...);
+// set LOG_PRETTY=1 in dev for a human-readable console writer instead.
+var logger zerolog.Logger
+
+func initLogger() {
+	var writer zerolog.ConsoleWriter
+	if os.Getenv("LOG_PRETTY") == "1" {
+		writer = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "15:04:05"}
+		logger = zerolog.New(writer).With().Timestamp().Logger()
+		return
+	}
+	logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+}
@@ -1,14 +1,8 @@
 package main
 
 import (
-	"fmt"
-	"io"
-	"log"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"time"
+	"sync/atomic"
 
 	"github.com/joho/godotenv" // Import the package
 )
@@ -18,121 +12,52 @@ const (
 	MAX_CONCURRENT_JOBS = 1
 )
 
-// Global job counter
-var requestID int = 0
-var jobQueue = make(chan struct{}, MAX_CONCURRENT_JOBS)
+// requestID is a request-scoped counter used only for log correlation
+// (not job IDs, which are random). atomic.Int64 because /probe, /jobs and
+// /convert-webp used to race on incrementing a bare int from concurrent
+// handlers.
+var requestID atomic.Int64
+
+func nextRequestID() int64 {
+	return requestID.Add(1)
+}
 
 func main() {
+	initLogger()
+
 	// 1. Load .env file
 	// Load() looks for .env in the current directory.
-	// We ignore the error so this code still works in production (Docker/Cloud) 
+	// We ignore the error so this code still works in production (Docker/Cloud)
 	// if variables are set directly in the OS.
 	if err := godotenv.Load(); err != nil {
-		log.Println("⚠️  No .env file found. Relying on system environment variables.")
+		logger.Warn().Msg("no .env file found, relying on system environment variables")
 	}
 
 	// 2. Health Check
-	http.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
-		log.Println("🏓 Ping received")
+	http.HandleFunc("/ping", instrumentRoute("/ping", func(w http.ResponseWriter, r *http.Request) {
+		logger.Info().Str("phase", "ping").Msg("ping received")
 		w.Write([]byte("pong"))
-	})
-
-	// 3. Conversion Endpoint
-	http.HandleFunc("/convert-webp", func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		requestID++
-		id := requestID
-
-		log.Printf("[#%d] 📥 New Request received from %s", id, r.RemoteAddr)
-
-		// --- Security ---
-		// READ SECRET FROM ENV HERE
-		workerSecret := os.Getenv("WORKER_SECRET")
-		
-		if workerSecret == "" {
-			log.Printf("[#%d] ❌ Fatal: WORKER_SECRET is not set in environment", id)
-			http.Error(w, "Server Configuration Error", http.StatusInternalServerError)
-			return
-		}
-
-		if r.Header.Get("Authorization") != "Bearer "+workerSecret {
-			log.Printf("[#%d] ⛔ Unauthorized attempt", id)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
-		// --- Queue ---
-		if len(jobQueue) >= MAX_CONCURRENT_JOBS {
-			log.Printf("[#%d] ⏳ Queue is full. Waiting for slot...", id)
-		}
-
-		jobQueue <- struct{}{}
-		defer func() { <-jobQueue }()
-		
-		queueWaitDuration := time.Since(start)
-		log.Printf("[#%d] ⚡ Slot acquired (Waited: %v). Reading file...", id, queueWaitDuration)
-
-		// --- File Read ---
-		file, header, err := r.FormFile("file")
-		if err != nil {
-			log.Printf("[#%d] ❌ Error reading form file: %v", id, err)
-			http.Error(w, "Failed to read file", http.StatusBadRequest)
-			return
-		}
-		defer file.Close()
-
-		fileSize := header.Size
-		log.Printf("[#%d] 📦 File: %s (Size: %.2f MB)", id, header.Filename, float64(fileSize)/1024/1024)
-
-		inputPath := filepath.Join("/tmp", fmt.Sprintf("input_%d_%s", id, header.Filename))
-		outFile, _ := os.Create(inputPath)
-		io.Copy(outFile, file)
-		outFile.Close()
-		defer os.Remove(inputPath)
+	}))
 
-		// --- FFmpeg ---
-		outputPath := inputPath + ".webp"
-		log.Printf("[#%d] 🎬 Starting FFmpeg conversion...", id)
-		ffmpegStart := time.Now()
+	// 3. Probe Endpoint (peer of the /jobs conversion pipeline, see probe.go)
+	http.HandleFunc("/probe", instrumentRoute("/probe", handleProbe))
 
-		cmd := exec.Command("ffmpeg", "-y",
-			"-i", inputPath,
-			"-t", "30",
-			"-c:v", "libwebp",
-			"-q:v", "50",
-			"-loop", "0",
-			"-preset", "default",
-			outputPath,
-		)
+	// 4. Async Job API: POST /jobs enqueues, GET /jobs/{id} polls status,
+	// GET /jobs/{id}/result streams the finished output. See jobs.go.
+	http.HandleFunc("/jobs", instrumentRoute("/jobs", handleJobs))
+	http.HandleFunc("/jobs/", instrumentRoute("/jobs/", handleJobs))
 
-		if output, err := cmd.CombinedOutput(); err != nil {
-			log.Printf("[#%d] ❌ FFmpeg Failed: %s", id, string(output))
-			http.Error(w, "Conversion failed", http.StatusInternalServerError)
-			return
-		}
-		
-		ffmpegDuration := time.Since(ffmpegStart)
-		log.Printf("[#%d] ✅ FFmpeg finished in %v. Sending result back...", id, ffmpegDuration)
+	// 5. Output profile discovery, see profiles.go.
+	http.HandleFunc("/profiles", instrumentRoute("/profiles", handleProfiles))
 
-		// --- Response ---
-		stat, _ := os.Stat(outputPath)
-		log.Printf("[#%d] 📤 Uploading result (%.2f MB)...", id, float64(stat.Size())/1024/1024)
+	// 6. Prometheus metrics, see metrics.go.
+	http.Handle("/metrics", handleMetrics)
 
-		w.Header().Set("Content-Type", "image/webp")
-		http.ServeFile(w, r, outputPath)
-		
-		os.Remove(outputPath) 
-		
-		totalDuration := time.Since(start)
-		log.Printf("[#%d] 🎉 Request Complete. Total Time: %v", id, totalDuration)
-	})
+	StartDispatcher(MAX_CONCURRENT_JOBS)
+	InitProbeSemaphore(MAX_CONCURRENT_JOBS)
 
-	log.Println("🚀 Verbose Worker Online on :8080")
+	logger.Info().Msg("worker online on :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+		logger.Fatal().Err(err).Msg("server exited")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// This file is the mediaio subsystem: it owns turning an inbound HTTP
+// request into a safely-staged local file, with the size/type/duration
+// guards the old inline `os.Create` + `io.Copy` in the handler didn't have.
+
+const (
+	// DefaultMaxUploadBytes caps how much of a multipart upload we'll
+	// buffer to /tmp before giving up. Overridable via MAX_UPLOAD_BYTES.
+	DefaultMaxUploadBytes int64 = 200 << 20 // 200 MB
+
+	// DefaultMaxDurationSeconds caps how long an input's ffprobe-reported
+	// duration may be before we refuse to convert it. Overridable via
+	// MAX_DURATION_SECONDS.
+	DefaultMaxDurationSeconds = 600.0 // 10 minutes
+)
+
+// allowedUploadTypes is the set of sniffed content-types we'll hand to
+// ffmpeg. Anything else is rejected before it ever touches /tmp.
+var allowedUploadTypes = map[string]bool{
+	"video/mp4":        true,
+	"video/webm":       true,
+	"video/quicktime":  true,
+	"video/x-matroska": true,
+	"image/gif":        true,
+	"image/png":        true,
+	"image/jpeg":       true,
+}
+
+// IngestError is a user-facing ingestion failure with the HTTP status it
+// should map to, so handlers can surface it as structured JSON instead of
+// a bare string.
+type IngestError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *IngestError) Error() string { return e.Message }
+
+func ingestErrorf(status int, format string, args ...any) *IngestError {
+	return &IngestError{StatusCode: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// MaxUploadBytes returns the configured cap, falling back to the default.
+func MaxUploadBytes() int64 {
+	if v := os.Getenv("MAX_UPLOAD_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxUploadBytes
+}
+
+// MaxDurationSeconds returns the configured cap, falling back to the default.
+func MaxDurationSeconds() float64 {
+	if v := os.Getenv("MAX_DURATION_SECONDS"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxDurationSeconds
+}
+
+// SaveUpload reads the "file" multipart field off r into a new temp file
+// under /tmp, enforcing a max size via http.MaxBytesReader and validating
+// the sniffed content-type against allowedUploadTypes. The caller owns
+// the returned path and must call cleanup() when done with it, even on
+// the error path where cleanup is a no-op.
+func SaveUpload(w http.ResponseWriter, r *http.Request, namePrefix string) (path string, cleanup func(), ingestErr *IngestError) {
+	noop := func() {}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadBytes())
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return "", noop, ingestErrorf(http.StatusBadRequest, "failed to read uploaded file: %v", err)
+	}
+	defer file.Close()
+
+	// Sniff the content-type from the first 512 bytes without losing them
+	// for the subsequent copy.
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowedUploadTypes[contentType] {
+		return "", noop, ingestErrorf(http.StatusUnsupportedMediaType, "unsupported content type: %s", contentType)
+	}
+
+	tmp, err := os.CreateTemp("", namePrefix+"_*")
+	if err != nil {
+		return "", noop, ingestErrorf(http.StatusInternalServerError, "failed to create temp file: %v", err)
+	}
+	cleanup = func() { os.Remove(tmp.Name()) }
+
+	if _, err := io.Copy(tmp, io.MultiReader(bytes.NewReader(head), file)); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", noop, ingestErrorf(http.StatusRequestEntityTooLarge, "upload too large or interrupted: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", noop, ingestErrorf(http.StatusInternalServerError, "failed to finalize upload: %v", err)
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// FetchRemoteFile downloads rawURL into a new temp file instead of handing
+// the URL to an external tool directly: it goes through checkPublicURL and
+// pinnedHTTPClient (see netguard.go) so the connection can't be rebound to
+// a private/metadata address after the guard's own DNS lookup, and caps
+// the transfer at MaxUploadBytes like any other ingested input. The caller
+// owns the returned path and must call cleanup(), even on the error path
+// where cleanup is a no-op.
+func FetchRemoteFile(ctx context.Context, rawURL, namePrefix string) (path string, cleanup func(), ingestErr *IngestError) {
+	noop := func() {}
+
+	parsed, pinnedIP, err := checkPublicURL(rawURL)
+	if err != nil {
+		return "", noop, ingestErrorf(http.StatusBadRequest, "rejected url: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return "", noop, ingestErrorf(http.StatusBadRequest, "invalid url: %v", err)
+	}
+	resp, err := pinnedHTTPClient(pinnedIP).Do(req)
+	if err != nil {
+		return "", noop, ingestErrorf(http.StatusBadGateway, "failed to fetch url: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", noop, ingestErrorf(http.StatusBadGateway, "GET %s: unexpected status %s", rawURL, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp("", namePrefix+"_*")
+	if err != nil {
+		return "", noop, ingestErrorf(http.StatusInternalServerError, "failed to create temp file: %v", err)
+	}
+	cleanup = func() { os.Remove(tmp.Name()) }
+
+	if _, err := copyLimited(tmp, resp.Body, MaxUploadBytes()); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", noop, ingestErrorf(http.StatusRequestEntityTooLarge, "download too large or interrupted: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", noop, ingestErrorf(http.StatusInternalServerError, "failed to finalize download: %v", err)
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// copyLimited copies from r to w, stopping after maxBytes+1 bytes and
+// returning an error if the source held more than maxBytes, so a streamed
+// remote-fetched source can't exhaust /tmp the way a multipart upload
+// could before MaxUploadBytes existed.
+func copyLimited(w io.Writer, r io.Reader, maxBytes int64) (int64, error) {
+	n, err := io.Copy(w, io.LimitReader(r, maxBytes+1))
+	if n > maxBytes {
+		return n, fmt.Errorf("source exceeds max upload size of %d bytes", maxBytes)
+	}
+	return n, err
+}
+
+// writeJSONError writes a structured {"error": "..."} body instead of the
+// plain-text http.Error default, so API clients can parse failures.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestMaxUploadBytesDefault(t *testing.T) {
+	os.Unsetenv("MAX_UPLOAD_BYTES")
+	if got := MaxUploadBytes(); got != DefaultMaxUploadBytes {
+		t.Errorf("MaxUploadBytes() = %d, want default %d", got, DefaultMaxUploadBytes)
+	}
+}
+
+func TestMaxUploadBytesOverride(t *testing.T) {
+	t.Setenv("MAX_UPLOAD_BYTES", "1024")
+	if got := MaxUploadBytes(); got != 1024 {
+		t.Errorf("MaxUploadBytes() = %d, want 1024", got)
+	}
+}
+
+func TestMaxUploadBytesInvalidFallsBackToDefault(t *testing.T) {
+	t.Setenv("MAX_UPLOAD_BYTES", "not-a-number")
+	if got := MaxUploadBytes(); got != DefaultMaxUploadBytes {
+		t.Errorf("MaxUploadBytes() = %d, want default %d on invalid input", got, DefaultMaxUploadBytes)
+	}
+}
+
+func TestMaxDurationSecondsDefault(t *testing.T) {
+	os.Unsetenv("MAX_DURATION_SECONDS")
+	if got := MaxDurationSeconds(); got != DefaultMaxDurationSeconds {
+		t.Errorf("MaxDurationSeconds() = %v, want default %v", got, DefaultMaxDurationSeconds)
+	}
+}
+
+func TestMaxDurationSecondsOverride(t *testing.T) {
+	t.Setenv("MAX_DURATION_SECONDS", "30")
+	if got := MaxDurationSeconds(); got != 30 {
+		t.Errorf("MaxDurationSeconds() = %v, want 30", got)
+	}
+}
+
+func TestAllowedUploadTypes(t *testing.T) {
+	allowed := []string{"video/mp4", "video/webm", "video/quicktime", "video/x-matroska", "image/gif", "image/png", "image/jpeg"}
+	for _, ct := range allowed {
+		if !allowedUploadTypes[ct] {
+			t.Errorf("expected %q to be allowed", ct)
+		}
+	}
+	if allowedUploadTypes["application/x-sh"] {
+		t.Errorf("expected application/x-sh to be disallowed")
+	}
+}
+
+func TestCopyLimitedWithinBound(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := copyLimited(&buf, bytes.NewReader([]byte("hello")), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("n = %d, want 5", n)
+	}
+}
+
+func TestCopyLimitedExceedsBound(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := copyLimited(&buf, bytes.NewReader([]byte("hello world")), 5)
+	if err == nil {
+		t.Fatalf("expected error when source exceeds max size")
+	}
+}
+
+func TestIngestErrorf(t *testing.T) {
+	err := ingestErrorf(422, "bad %s", "input")
+	if err.StatusCode != 422 {
+		t.Errorf("StatusCode = %d, want 422", err.StatusCode)
+	}
+	if err.Error() != "bad input" {
+		t.Errorf("Error() = %q, want %q", err.Error(), "bad input")
+	}
+}
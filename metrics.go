@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kcat_requests_total",
+		Help: "Total HTTP requests handled, by route and status code.",
+	}, []string{"route", "status"})
+
+	queueWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kcat_queue_wait_seconds",
+		Help:    "Time a job spent queued before a dispatcher worker picked it up.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	ffmpegDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kcat_ffmpeg_duration_seconds",
+		Help:    "Wall-clock time ffmpeg spent converting a job, by profile.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"profile"})
+
+	inputBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kcat_input_bytes",
+		Help:    "Size of uploaded input files.",
+		Buckets: prometheus.ExponentialBuckets(1<<10, 4, 10), // 1KiB .. ~256MiB
+	})
+
+	outputBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kcat_output_bytes",
+		Help:    "Size of produced output files.",
+		Buckets: prometheus.ExponentialBuckets(1<<10, 4, 10),
+	})
+
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kcat_queue_depth",
+		Help: "Number of jobs currently waiting in jobWorkQueue.",
+	})
+
+	jobsInflight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kcat_jobs_inflight",
+		Help: "Number of jobs currently being converted by dispatcher workers.",
+	})
+)
+
+// handleMetrics exposes the counters/histograms/gauges above for scraping.
+var handleMetrics = promhttp.Handler()
+
+// statusRecorder wraps a ResponseWriter so instrumentRoute can observe the
+// status code a handler actually wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes through to the wrapped writer so streaming handlers such as
+// /jobs/{id}/events still see an http.Flusher.
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// instrumentRoute wraps a handler so every request against it is counted
+// in kcat_requests_total{route,status}.
+func instrumentRoute(route string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		requestsTotal.WithLabelValues(route, strconv.Itoa(rec.status)).Inc()
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCheckPublicURLRejectsBadScheme(t *testing.T) {
+	if _, _, err := checkPublicURL("ftp://example.com/file"); err == nil {
+		t.Errorf("expected ftp:// to be rejected")
+	}
+}
+
+func TestCheckPublicURLRejectsMetadataHost(t *testing.T) {
+	if _, _, err := checkPublicURL("http://169.254.169.254/latest/meta-data/"); err == nil {
+		t.Errorf("expected the link-local metadata address to be rejected")
+	}
+}
+
+func TestCheckPublicURLRejectsLoopback(t *testing.T) {
+	if _, _, err := checkPublicURL("http://127.0.0.1:8080/"); err == nil {
+		t.Errorf("expected loopback to be rejected")
+	}
+}
+
+func TestCheckPublicURLRejectsPrivateIP(t *testing.T) {
+	if _, _, err := checkPublicURL("http://10.0.0.5/"); err == nil {
+		t.Errorf("expected a private address to be rejected")
+	}
+}
+
+func TestCheckPublicURLAcceptsPublicIP(t *testing.T) {
+	parsed, ip, err := checkPublicURL("http://93.184.216.34/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Host != "93.184.216.34" {
+		t.Errorf("parsed.Host = %q, want %q", parsed.Host, "93.184.216.34")
+	}
+	if ip.String() != "93.184.216.34" {
+		t.Errorf("ip = %v, want 93.184.216.34", ip)
+	}
+}
+
+func TestIsDisallowedPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"169.254.169.254", true},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"0.0.0.0", true},
+		{"8.8.8.8", false},
+		{"93.184.216.34", false},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("net.ParseIP(%q) failed", tt.ip)
+		}
+		if got := isDisallowedPublicIP(ip); got != tt.want {
+			t.Errorf("isDisallowedPublicIP(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// localFilePrefix marks an ObjectRef.URL as a local filesystem path
+// rather than an HTTP(S) URL, e.g. "file:///out.webp".
+const localFilePrefix = "file://"
+
+// localStagingRoot is the only directory LocalStore is allowed to touch.
+// ObjectRef can come straight from a request body (see
+// handleCreateRemoteJob), so a "file://" ref must never be able to name
+// an arbitrary host path — it's always resolved relative to this root,
+// never as an absolute path the caller chose.
+var localStagingRoot = filepath.Join(os.TempDir(), "kcat-local-store")
+
+func init() {
+	os.MkdirAll(localStagingRoot, 0o700)
+}
+
+// resolveLocalPath maps a "file://" ObjectRef.URL onto a path confined to
+// localStagingRoot, rejecting anything that would escape it (a leading
+// "/" or a "../" is just more path to clean away, not a way out).
+func resolveLocalPath(ref ObjectRef) (string, error) {
+	rel := strings.TrimPrefix(ref.URL, localFilePrefix)
+	if rel == "" {
+		return "", fmt.Errorf("file ref has an empty path")
+	}
+	full := filepath.Join(localStagingRoot, filepath.Clean("/"+rel))
+	if full != localStagingRoot && !strings.HasPrefix(full, localStagingRoot+string(os.PathSeparator)) {
+		return "", fmt.Errorf("file ref escapes the local staging directory")
+	}
+	return full, nil
+}
+
+// ObjectRef points at an input/output location for a remote (non-multipart)
+// job: either an object in S3 or a plain HTTP(S) URL. Exactly one of the
+// two should be set.
+type ObjectRef struct {
+	S3  *S3Ref `json:"s3,omitempty"`
+	URL string `json:"url,omitempty"`
+}
+
+// S3Ref identifies a single object in a bucket.
+type S3Ref struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+func (r ObjectRef) String() string {
+	if r.S3 != nil {
+		return fmt.Sprintf("s3://%s/%s", r.S3.Bucket, r.S3.Key)
+	}
+	return r.URL
+}
+
+// ObjectStore abstracts where a remote job's input comes from and its
+// output goes to, so the conversion path doesn't need to know whether
+// it's talking to S3, a plain HTTP(S) URL, or local disk.
+type ObjectStore interface {
+	Get(ctx context.Context, ref ObjectRef) (io.ReadCloser, error)
+	Put(ctx context.Context, ref ObjectRef, body io.Reader, contentType string) (etag string, err error)
+}
+
+// storeForRef picks the right ObjectStore implementation for a ref's
+// shape. S3 refs go to S3Store; "file://" URLs go to LocalStore; any
+// other URL goes to HTTPStore (read-only).
+func storeForRef(ref ObjectRef) (ObjectStore, error) {
+	switch {
+	case ref.S3 != nil:
+		return NewS3Store(), nil
+	case strings.HasPrefix(ref.URL, localFilePrefix):
+		return LocalStore{}, nil
+	case ref.URL != "":
+		return HTTPStore{}, nil
+	default:
+		return nil, fmt.Errorf("object ref must set either s3 or url")
+	}
+}
+
+// HTTPStore reads from a plain HTTP(S) URL. It only supports Get; there's
+// no generic "PUT this URL" destination, so remote jobs must use an S3
+// destination if they want the worker to upload the result somewhere.
+type HTTPStore struct{}
+
+func (HTTPStore) Get(ctx context.Context, ref ObjectRef) (io.ReadCloser, error) {
+	parsed, pinnedIP, err := checkPublicURL(ref.URL)
+	if err != nil {
+		return nil, fmt.Errorf("rejected source url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pinnedHTTPClient(pinnedIP).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected status %s", ref.URL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (HTTPStore) Put(ctx context.Context, ref ObjectRef, body io.Reader, contentType string) (string, error) {
+	return "", fmt.Errorf("HTTPStore does not support Put; use an s3 destination")
+}
+
+// LocalStore reads/writes a plain filesystem path, addressed via a
+// "file://" ObjectRef.URL. It exists so the ObjectStore abstraction
+// covers the original local-/tmp-staging behavior as just another
+// backend rather than a special case.
+type LocalStore struct{}
+
+func (LocalStore) Get(ctx context.Context, ref ObjectRef) (io.ReadCloser, error) {
+	path, err := resolveLocalPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (LocalStore) Put(ctx context.Context, ref ObjectRef, body io.Reader, contentType string) (string, error) {
+	path, err := resolveLocalPath(ref)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, body); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// S3Store is backed by aws-sdk-go-v2, using the default credential chain
+// (env vars, shared config, instance role, etc).
+type S3Store struct {
+	client *s3.Client
+}
+
+func NewS3Store() *S3Store {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		// Deferred to request time: Get/Put will fail with a clear error
+		// rather than panicking worker startup over a missing AWS config.
+		return &S3Store{}
+	}
+	return &S3Store{client: s3.NewFromConfig(cfg)}
+}
+
+func (s *S3Store) Get(ctx context.Context, ref ObjectRef) (io.ReadCloser, error) {
+	if s.client == nil || ref.S3 == nil {
+		return nil, fmt.Errorf("s3 store is not configured")
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(ref.S3.Bucket),
+		Key:    aws.String(ref.S3.Key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 GetObject %s: %w", ref, err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, ref ObjectRef, body io.Reader, contentType string) (string, error) {
+	if s.client == nil || ref.S3 == nil {
+		return "", fmt.Errorf("s3 store is not configured")
+	}
+	out, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(ref.S3.Bucket),
+		Key:         aws.String(ref.S3.Key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("s3 PutObject %s: %w", ref, err)
+	}
+	return aws.ToString(out.ETag), nil
+}
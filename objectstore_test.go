@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreForRefS3(t *testing.T) {
+	store, err := storeForRef(ObjectRef{S3: &S3Ref{Bucket: "b", Key: "k"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.(*S3Store); !ok {
+		t.Errorf("got %T, want *S3Store", store)
+	}
+}
+
+func TestStoreForRefLocal(t *testing.T) {
+	store, err := storeForRef(ObjectRef{URL: "file://foo.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.(LocalStore); !ok {
+		t.Errorf("got %T, want LocalStore", store)
+	}
+}
+
+func TestStoreForRefHTTP(t *testing.T) {
+	store, err := storeForRef(ObjectRef{URL: "https://example.com/input.mp4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.(HTTPStore); !ok {
+		t.Errorf("got %T, want HTTPStore", store)
+	}
+}
+
+func TestStoreForRefEmpty(t *testing.T) {
+	if _, err := storeForRef(ObjectRef{}); err == nil {
+		t.Errorf("expected error for an empty object ref")
+	}
+}
+
+func TestResolveLocalPathStaysUnderRoot(t *testing.T) {
+	path, err := resolveLocalPath(ObjectRef{URL: "file://job_abc/out.webp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(path, localStagingRoot+string(os.PathSeparator)) {
+		t.Errorf("resolved path %q escaped localStagingRoot %q", path, localStagingRoot)
+	}
+	if filepath.Base(path) != "out.webp" {
+		t.Errorf("resolved path %q, want it to end in out.webp", path)
+	}
+}
+
+func TestResolveLocalPathConfinesTraversalAttempts(t *testing.T) {
+	// None of these should escape localStagingRoot: an absolute path or a
+	// "../" climb is just more path to clean away relative to the root,
+	// never a way to name an arbitrary host path like
+	// /root/.ssh/authorized_keys.
+	tests := []string{
+		"file:///root/.ssh/authorized_keys",
+		"file://../../../../etc/cron.d/x",
+		"file://../../outside.txt",
+	}
+	for _, raw := range tests {
+		path, err := resolveLocalPath(ObjectRef{URL: raw})
+		if err != nil {
+			t.Fatalf("resolveLocalPath(%q) unexpected error: %v", raw, err)
+		}
+		if !strings.HasPrefix(path, localStagingRoot+string(os.PathSeparator)) {
+			t.Errorf("resolveLocalPath(%q) = %q, escaped localStagingRoot %q", raw, path, localStagingRoot)
+		}
+	}
+}
+
+func TestResolveLocalPathRejectsEmpty(t *testing.T) {
+	if _, err := resolveLocalPath(ObjectRef{URL: localFilePrefix}); err == nil {
+		t.Errorf("expected error for a file:// ref with an empty path")
+	}
+}
+
+func TestLocalStoreGetPutRoundTrip(t *testing.T) {
+	ref := ObjectRef{URL: "file://roundtrip_test.txt"}
+	store := LocalStore{}
+
+	if _, err := store.Put(context.Background(), ref, strings.NewReader("hello"), "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	resolved, _ := resolveLocalPath(ref)
+	defer os.Remove(resolved)
+
+	rc, err := store.Get(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer rc.Close()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+}
+
+func TestHTTPStoreGetRejectsDisallowedHost(t *testing.T) {
+	store := HTTPStore{}
+	_, err := store.Get(context.Background(), ObjectRef{URL: "http://169.254.169.254/latest/meta-data/"})
+	if err == nil {
+		t.Errorf("expected Get to reject a link-local/metadata url")
+	}
+}
+
+func TestHTTPStoreGetRejectsNonHTTPScheme(t *testing.T) {
+	store := HTTPStore{}
+	_, err := store.Get(context.Background(), ObjectRef{URL: "file:///etc/passwd"})
+	if err == nil {
+		t.Errorf("expected Get to reject a non-http(s) scheme")
+	}
+}
+
+func TestNewS3StoreNeverNil(t *testing.T) {
+	if store := NewS3Store(); store == nil {
+		t.Errorf("NewS3Store() returned nil")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ProbeFormat mirrors the "format" object in ffprobe's JSON output.
+type ProbeFormat struct {
+	Filename   string `json:"filename"`
+	FormatName string `json:"format_name"`
+	Duration   string `json:"duration"`
+	Size       string `json:"size"`
+	BitRate    string `json:"bit_rate"`
+	ProbeScore int    `json:"probe_score"`
+}
+
+// ProbeStream mirrors a single entry in ffprobe's "streams" array. Only the
+// fields callers have actually needed so far are pulled out; anything else
+// ffprobe reports is dropped when its output is parsed.
+type ProbeStream struct {
+	Index      int    `json:"index"`
+	CodecName  string `json:"codec_name"`
+	CodecType  string `json:"codec_type"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	RFrameRate string `json:"r_frame_rate,omitempty"`
+	BitRate    string `json:"bit_rate,omitempty"`
+	Duration   string `json:"duration,omitempty"`
+}
+
+// ProbeResult is the parsed response of `ffprobe -show_format -show_streams`.
+type ProbeResult struct {
+	Streams []ProbeStream `json:"streams"`
+	Format  ProbeFormat   `json:"format"`
+}
+
+// DurationSeconds returns the probed duration, preferring the format-level
+// value (present for basically every container ffprobe understands) and
+// falling back to 0 if it's missing or unparsable.
+func (p *ProbeResult) DurationSeconds() float64 {
+	var d float64
+	fmt.Sscanf(p.Format.Duration, "%f", &d)
+	return d
+}
+
+// ProbeFile shells out to ffprobe against a local file path, enforcing the
+// given timeout so a malformed/huge input can't hang a worker slot forever.
+func ProbeFile(parent context.Context, path string, timeout time.Duration) (*ProbeResult, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("ffprobe timed out after %v", timeout)
+		}
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var result ProbeResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return &result, nil
+}
+
+// probeSemaphore bounds concurrent /probe requests the same way
+// jobWorkQueue bounds conversions, so a burst of probes can't run
+// unlimited concurrent ffprobe processes alongside MAX_CONCURRENT_JOBS
+// conversions. Sized and started once from main via InitProbeSemaphore.
+var probeSemaphore chan struct{}
+
+// InitProbeSemaphore sizes the /probe concurrency limiter. Called once
+// from main alongside StartDispatcher.
+func InitProbeSemaphore(n int) {
+	probeSemaphore = make(chan struct{}, n)
+}
+
+// handleProbe accepts an uploaded file (multipart field "file") or a
+// "url" query parameter and returns the parsed ffprobe metadata as JSON.
+// It shares the same Bearer-token auth as the /jobs routes so callers can
+// pre-flight an upload (duration, codec, resolution) before paying for a
+// full webp conversion.
+func handleProbe(w http.ResponseWriter, r *http.Request) {
+	id := nextRequestID()
+	l := logger.With().Int64("request_id", id).Str("remote_addr", r.RemoteAddr).Logger()
+	l.Info().Str("phase", "probe_start").Msg("probe request received")
+
+	workerSecret := os.Getenv("WORKER_SECRET")
+	if workerSecret == "" {
+		l.Error().Msg("WORKER_SECRET is not set in environment")
+		writeJSONError(w, http.StatusInternalServerError, "Server Configuration Error")
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+workerSecret {
+		l.Warn().Msg("unauthorized attempt")
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var inputPath string
+
+	if sourceURL := r.URL.Query().Get("url"); sourceURL != "" {
+		l.Info().Str("url", sourceURL).Msg("probing remote url")
+
+		// Fetched ourselves (rather than handed to ffprobe as-is) so the
+		// SSRF guard's own DNS lookup is the one the connection actually
+		// uses — ffprobe resolving the hostname a second time would open
+		// a DNS-rebinding gap the guard can't see.
+		savedPath, cleanup, ingestErr := FetchRemoteFile(r.Context(), sourceURL, "probe_url")
+		if ingestErr != nil {
+			l.Warn().Str("url", sourceURL).Err(ingestErr).Msg("rejected probe url")
+			writeJSONError(w, ingestErr.StatusCode, ingestErr.Message)
+			return
+		}
+		defer cleanup()
+		inputPath = savedPath
+	} else {
+		if r.Method != "POST" {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		savedPath, cleanup, ingestErr := SaveUpload(w, r, "probe")
+		if ingestErr != nil {
+			l.Warn().Str("phase", "ingest").Err(ingestErr).Msg("ingestion failed")
+			writeJSONError(w, ingestErr.StatusCode, ingestErr.Message)
+			return
+		}
+		defer cleanup()
+		inputPath = savedPath
+	}
+
+	select {
+	case probeSemaphore <- struct{}{}:
+		defer func() { <-probeSemaphore }()
+	default:
+		l.Warn().Msg("probe rejected: too many concurrent probes")
+		writeJSONError(w, http.StatusServiceUnavailable, "Too many concurrent probes, try again later")
+		return
+	}
+
+	result, err := ProbeFile(r.Context(), inputPath, 15*time.Second)
+	if err != nil {
+		l.Error().Str("phase", "ffprobe").Err(err).Msg("ffprobe failed")
+		writeJSONError(w, http.StatusInternalServerError, "Probe failed")
+		return
+	}
+
+	l.Info().Str("phase", "probe_done").Int("streams", len(result.Streams)).Float64("duration_seconds", result.DurationSeconds()).Msg("probe complete")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+)
+
+// Profile describes one output shape the worker can produce: its file
+// extension (or directory layout, for segmented output), MIME type, and
+// the ffmpeg arguments that turn an input file into that output. Adding
+// a new output format is a matter of registering a Profile here rather
+// than branching inside the conversion path.
+type Profile struct {
+	Name string
+
+	// Extension is appended to the job's input path to get its output
+	// path. Ignored when IsDirectory is true.
+	Extension string
+	MIMEType  string
+
+	// IsDirectory is true for profiles whose output is a directory of
+	// files (currently just "hls") rather than a single file.
+	IsDirectory bool
+
+	// BuildArgs returns the ffmpeg arguments to run after `-i <input>`,
+	// ending in the output path/pattern.
+	BuildArgs func(outputPath string) []string
+}
+
+// DefaultProfile is used when a request doesn't specify one, preserving
+// the worker's original sticker-conversion behavior.
+const DefaultProfile = "webp-sticker"
+
+var profiles = map[string]Profile{
+	"webp-sticker": {
+		Name:      "webp-sticker",
+		Extension: ".webp",
+		MIMEType:  "image/webp",
+		BuildArgs: func(outputPath string) []string {
+			return []string{
+				"-t", "30",
+				"-c:v", "libwebp",
+				"-q:v", "50",
+				"-loop", "0",
+				"-preset", "default",
+				outputPath,
+			}
+		},
+	},
+	"webp-static": {
+		Name:      "webp-static",
+		Extension: ".webp",
+		MIMEType:  "image/webp",
+		BuildArgs: func(outputPath string) []string {
+			return []string{
+				"-frames:v", "1",
+				"-c:v", "libwebp",
+				outputPath,
+			}
+		},
+	},
+	"mp4-preview": {
+		Name:      "mp4-preview",
+		Extension: ".mp4",
+		MIMEType:  "video/mp4",
+		BuildArgs: func(outputPath string) []string {
+			return []string{
+				"-t", "30",
+				"-c:v", "libx264",
+				"-c:a", "aac",
+				"-movflags", "+faststart",
+				outputPath,
+			}
+		},
+	},
+	"thumbs": {
+		Name:      "thumbs",
+		Extension: ".png",
+		MIMEType:  "image/png",
+		BuildArgs: func(outputPath string) []string {
+			return []string{
+				"-vf", "select='not(mod(n\\,30))',scale=320:-1,tile=4x4",
+				"-frames:v", "1",
+				outputPath,
+			}
+		},
+	},
+	"hls": {
+		Name:        "hls",
+		MIMEType:    "application/vnd.apple.mpegurl",
+		IsDirectory: true,
+		BuildArgs: func(outputDir string) []string {
+			return []string{
+				"-c:v", "libx264",
+				"-c:a", "aac",
+				"-hls_time", "4",
+				"-hls_list_size", "0",
+				"-hls_segment_filename", filepath.Join(outputDir, "seg_%03d.ts"),
+				filepath.Join(outputDir, "index.m3u8"),
+			}
+		},
+	},
+}
+
+// handleProfiles implements GET /profiles, a discovery endpoint so
+// callers can validate a profile name client-side before uploading.
+func handleProfiles(w http.ResponseWriter, r *http.Request) {
+	type profileInfo struct {
+		Name      string `json:"name"`
+		Extension string `json:"extension,omitempty"`
+		MIMEType  string `json:"mime_type"`
+		Directory bool   `json:"directory,omitempty"`
+	}
+
+	list := make([]profileInfo, 0, len(profiles))
+	for _, p := range profiles {
+		list = append(list, profileInfo{
+			Name:      p.Name,
+			Extension: p.Extension,
+			MIMEType:  p.MIMEType,
+			Directory: p.IsDirectory,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileBuildArgsEndsWithOutputPath(t *testing.T) {
+	for name, p := range profiles {
+		if p.IsDirectory {
+			continue
+		}
+		args := p.BuildArgs("/tmp/out" + p.Extension)
+		if len(args) == 0 {
+			t.Errorf("profile %q: BuildArgs returned no arguments", name)
+			continue
+		}
+		if got := args[len(args)-1]; got != "/tmp/out"+p.Extension {
+			t.Errorf("profile %q: last arg = %q, want output path %q", name, got, "/tmp/out"+p.Extension)
+		}
+	}
+}
+
+func TestHLSProfileBuildArgsUsesOutputDirectory(t *testing.T) {
+	p := profiles["hls"]
+	if !p.IsDirectory {
+		t.Fatalf("hls profile should have IsDirectory = true")
+	}
+
+	args := p.BuildArgs("/tmp/job_abc")
+	joined := strings.Join(args, " ")
+
+	wantPlaylist := filepath.Join("/tmp/job_abc", "index.m3u8")
+	wantSegments := filepath.Join("/tmp/job_abc", "seg_%03d.ts")
+
+	if !strings.Contains(joined, wantPlaylist) {
+		t.Errorf("BuildArgs() = %v, want it to contain playlist path %q", args, wantPlaylist)
+	}
+	if !strings.Contains(joined, wantSegments) {
+		t.Errorf("BuildArgs() = %v, want it to contain segment pattern %q", args, wantSegments)
+	}
+}
+
+func TestDefaultProfileIsRegistered(t *testing.T) {
+	if _, ok := profiles[DefaultProfile]; !ok {
+		t.Errorf("DefaultProfile %q is not registered in profiles", DefaultProfile)
+	}
+}
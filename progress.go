@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ProgressEvent is what /jobs/{id}/events emits over SSE. It's derived
+// from ffmpeg's `-progress pipe:1` key=value stream plus the job's
+// ffprobe-reported duration.
+type ProgressEvent struct {
+	Status        string  `json:"status"` // "progress" or "done"
+	Percent       float64 `json:"percent"`
+	FPS           float64 `json:"fps,omitempty"`
+	ETASeconds    float64 `json:"eta_seconds,omitempty"`
+	CurrentTimeMs int64   `json:"current_time_ms"`
+}
+
+// progressHub fans out progress events for a job to however many
+// subscribers are currently listening on /jobs/{id}/events. A job keeps
+// converting even with zero subscribers; the hub just drops events no
+// one is there to read.
+type progressHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan ProgressEvent]struct{}
+}
+
+var hub = &progressHub{subs: make(map[string]map[chan ProgressEvent]struct{})}
+
+func (h *progressHub) subscribe(jobID string) chan ProgressEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan ProgressEvent, 8)
+	if h.subs[jobID] == nil {
+		h.subs[jobID] = make(map[chan ProgressEvent]struct{})
+	}
+	h.subs[jobID][ch] = struct{}{}
+	return ch
+}
+
+func (h *progressHub) unsubscribe(jobID string, ch chan ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[jobID], ch)
+	if len(h.subs[jobID]) == 0 {
+		delete(h.subs, jobID)
+	}
+}
+
+func (h *progressHub) publish(jobID string, ev ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[jobID] {
+		select {
+		case ch <- ev:
+		default: // slow subscriber, drop rather than block the worker
+		}
+	}
+}
+
+// parseProgressStream reads ffmpeg's `-progress pipe:1` output line by
+// line, accumulating one key=value block at a time (terminated by the
+// `progress=` key), and calls onEvent for each completed block.
+func parseProgressStream(r *bufio.Scanner, durationSeconds float64, onEvent func(ProgressEvent, bool)) {
+	block := make(map[string]string)
+	for r.Scan() {
+		line := r.Text()
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		block[key] = value
+
+		if key != "progress" {
+			continue
+		}
+
+		ev := buildProgressEvent(block, durationSeconds)
+		done := value == "end"
+		if done {
+			ev.Status = "done"
+			ev.Percent = 100
+		}
+		onEvent(ev, done)
+		block = make(map[string]string)
+	}
+}
+
+func buildProgressEvent(block map[string]string, durationSeconds float64) ProgressEvent {
+	ev := ProgressEvent{Status: "progress"}
+
+	if ms, err := strconv.ParseInt(block["out_time_ms"], 10, 64); err == nil {
+		ev.CurrentTimeMs = ms / 1000 // ffmpeg reports microseconds despite the name
+	}
+	if fps, err := strconv.ParseFloat(block["fps"], 64); err == nil {
+		ev.FPS = fps
+	}
+
+	if durationSeconds > 0 {
+		elapsed := float64(ev.CurrentTimeMs) / 1000
+		ev.Percent = (elapsed / durationSeconds) * 100
+		if ev.Percent > 100 {
+			ev.Percent = 100
+		}
+		if ev.FPS > 0 {
+			remaining := durationSeconds - elapsed
+			if remaining > 0 {
+				ev.ETASeconds = remaining
+			}
+		}
+	}
+	return ev
+}
+
+// handleJobEvents implements GET /jobs/{id}/events, streaming percent/fps/eta
+// updates as Server-Sent Events until the job reaches a terminal state or the
+// client disconnects. The conversion itself is unaffected by disconnects
+// since it runs independently in the dispatcher.
+func handleJobEvents(w http.ResponseWriter, r *http.Request, jobID string) {
+	job, ok := jobStore.Get(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	if job.Status == JobDone || job.Status == JobFailed {
+		writeSSE(w, ProgressEvent{Status: "done", Percent: 100})
+		flusher.Flush()
+		return
+	}
+
+	ch := hub.subscribe(jobID)
+	defer hub.unsubscribe(jobID, ch)
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Str("job_id", jobID).Str("phase", "events").Msg("subscriber disconnected")
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeSSE(w, ev)
+			flusher.Flush()
+			if ev.Status == "done" {
+				return
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, ev ProgressEvent) {
+	payload, _ := json.Marshal(ev)
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+}
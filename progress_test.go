@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestBuildProgressEvent(t *testing.T) {
+	block := map[string]string{
+		"out_time_ms": "5000000", // 5s, reported in microseconds
+		"fps":         "24.5",
+	}
+
+	ev := buildProgressEvent(block, 10)
+
+	if ev.Status != "progress" {
+		t.Errorf("Status = %q, want %q", ev.Status, "progress")
+	}
+	if ev.CurrentTimeMs != 5000 {
+		t.Errorf("CurrentTimeMs = %d, want 5000", ev.CurrentTimeMs)
+	}
+	if ev.FPS != 24.5 {
+		t.Errorf("FPS = %v, want 24.5", ev.FPS)
+	}
+	if ev.Percent != 50 {
+		t.Errorf("Percent = %v, want 50", ev.Percent)
+	}
+	if ev.ETASeconds != 5 {
+		t.Errorf("ETASeconds = %v, want 5", ev.ETASeconds)
+	}
+}
+
+func TestBuildProgressEventClampsPercent(t *testing.T) {
+	block := map[string]string{"out_time_ms": "20000000"} // 20s against a 10s duration
+
+	ev := buildProgressEvent(block, 10)
+
+	if ev.Percent != 100 {
+		t.Errorf("Percent = %v, want 100", ev.Percent)
+	}
+}
+
+func TestBuildProgressEventZeroDuration(t *testing.T) {
+	block := map[string]string{"out_time_ms": "5000000", "fps": "24"}
+
+	ev := buildProgressEvent(block, 0)
+
+	if ev.Percent != 0 {
+		t.Errorf("Percent = %v, want 0 when duration is unknown", ev.Percent)
+	}
+	if ev.ETASeconds != 0 {
+		t.Errorf("ETASeconds = %v, want 0 when duration is unknown", ev.ETASeconds)
+	}
+}
+
+func TestParseProgressStream(t *testing.T) {
+	input := "out_time_ms=5000000\nfps=24.5\nprogress=continue\n" +
+		"out_time_ms=10000000\nfps=25\nprogress=end\n"
+	scanner := bufio.NewScanner(strings.NewReader(input))
+
+	var events []ProgressEvent
+	var doneFlags []bool
+	parseProgressStream(scanner, 10, func(ev ProgressEvent, done bool) {
+		events = append(events, ev)
+		doneFlags = append(doneFlags, done)
+	})
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if doneFlags[0] {
+		t.Errorf("first event marked done, want not done")
+	}
+	if !doneFlags[1] {
+		t.Errorf("second event not marked done, want done")
+	}
+	if events[1].Status != "done" || events[1].Percent != 100 {
+		t.Errorf("final event = %+v, want Status=done Percent=100", events[1])
+	}
+}
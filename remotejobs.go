@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// RemoteJobRequest is the JSON body for the object-store job API: instead
+// of a multipart upload, the caller points the worker at where to fetch
+// the input and where to put the result.
+type RemoteJobRequest struct {
+	Source      ObjectRef `json:"source"`
+	Destination ObjectRef `json:"destination"`
+	Profile     string    `json:"profile"`
+}
+
+// handleCreateRemoteJob implements the JSON-body variant of POST /jobs:
+// the worker streams the input from an ObjectStore (S3 or a plain URL)
+// instead of requiring the caller to shuttle the file through this API,
+// and uploads the result back to the destination store when done.
+func handleCreateRemoteJob(w http.ResponseWriter, r *http.Request) {
+	id := nextRequestID()
+	l := logger.With().Int64("request_id", id).Str("remote_addr", r.RemoteAddr).Logger()
+	l.Info().Str("phase", "submit_remote").Msg("new remote job submission")
+
+	if !requireAuth(w, r, &l) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req RemoteJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		l.Warn().Err(err).Msg("invalid request body")
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	profileName := req.Profile
+	if profileName == "" {
+		profileName = DefaultProfile
+	}
+	profile, ok := profiles[profileName]
+	if !ok {
+		l.Warn().Str("profile", profileName).Msg("unknown profile")
+		writeJSONError(w, http.StatusBadRequest, "Unknown profile: "+profileName)
+		return
+	}
+	if profile.IsDirectory {
+		writeJSONError(w, http.StatusBadRequest, "directory-producing profiles (e.g. hls) aren't supported via the remote API yet")
+		return
+	}
+	// file:// refs address worker-managed staging paths, not anything a
+	// caller should be able to name over the wire, so the public API
+	// never accepts one: a request body must only ever point at s3 or a
+	// plain http(s) url.
+	if strings.HasPrefix(req.Source.URL, localFilePrefix) || strings.HasPrefix(req.Destination.URL, localFilePrefix) {
+		writeJSONError(w, http.StatusBadRequest, "file:// refs are not allowed via the remote job API")
+		return
+	}
+
+	sourceStore, err := storeForRef(req.Source)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid source: "+err.Error())
+		return
+	}
+	if _, err := storeForRef(req.Destination); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid destination: "+err.Error())
+		return
+	}
+
+	body, err := sourceStore.Get(r.Context(), req.Source)
+	if err != nil {
+		l.Warn().Str("phase", "fetch_source").Err(err).Msg("could not fetch source object")
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch source: "+err.Error())
+		return
+	}
+	defer body.Close()
+
+	jobID := newJobID()
+	tmp, err := os.CreateTemp("", "job_"+jobID+"_*")
+	if err != nil {
+		l.Error().Err(err).Msg("failed to create temp file")
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	inputPath := tmp.Name()
+	n, copyErr := copyLimited(tmp, body, MaxUploadBytes())
+	tmp.Close()
+	if copyErr != nil {
+		os.Remove(inputPath)
+		l.Warn().Err(copyErr).Msg("failed to stage source object")
+		writeJSONError(w, http.StatusBadGateway, "failed to stage source: "+copyErr.Error())
+		return
+	}
+	inputBytes.Observe(float64(n))
+
+	if probed, err := ProbeFile(r.Context(), inputPath, 15*time.Second); err == nil {
+		if max := MaxDurationSeconds(); probed.DurationSeconds() > max {
+			os.Remove(inputPath)
+			l.Warn().Float64("duration_seconds", probed.DurationSeconds()).Float64("cap_seconds", max).Msg("rejected: duration exceeds cap")
+			writeJSONError(w, http.StatusUnprocessableEntity, "input duration exceeds the configured cap")
+			return
+		}
+	} else {
+		l.Warn().Str("phase", "probe").Err(err).Msg("could not pre-flight duration")
+	}
+
+	dest := req.Destination
+	job := &Job{
+		ID:          jobID,
+		Status:      JobQueued,
+		Profile:     profileName,
+		Filename:    req.Source.String(),
+		InputPath:   inputPath,
+		Destination: &dest,
+		CreatedAt:   time.Now(),
+	}
+	jobStore.Create(job)
+
+	select {
+	case jobWorkQueue <- job:
+		queueDepth.Inc()
+		l.Info().Str("job_id", jobID).Msg("remote job queued")
+	default:
+		jobStore.Update(jobID, func(j *Job) {
+			j.Status = JobFailed
+			j.Error = "queue is full"
+			j.FinishedAt = time.Now()
+		})
+		l.Error().Str("job_id", jobID).Msg("job rejected: queue full")
+		writeJSONError(w, http.StatusServiceUnavailable, "Queue is full, try again later")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id":     jobID,
+		"status_url": "/jobs/" + jobID,
+	})
+}